@@ -0,0 +1,76 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+// ansiFilter is a streaming filter for ANSI escape sequences in subprocess
+// output. When keepANSI is true (the underlying writer is a real
+// terminal), SGR (color/formatting) sequences are preserved but
+// cursor-movement and screen-clearing sequences are stripped so they can't
+// corrupt the status line. When keepANSI is false, every CSI sequence is
+// stripped so output written to a log file stays readable.
+//
+// filter may be called repeatedly with successive chunks of a stream, so
+// pending buffers any escape sequence that hasn't been terminated yet.
+type ansiFilter struct {
+	keepANSI bool
+	pending  []byte
+}
+
+// csiFinalByte reports whether b is the final byte of a CSI sequence
+// (\x1b[ ... final), per ECMA-48: a byte in the range 0x40-0x7e.
+func csiFinalByte(b byte) bool {
+	return b >= 0x40 && b <= 0x7e
+}
+
+// filter returns p with escape sequences classified and either passed
+// through or stripped, per keepANSI.
+func (f *ansiFilter) filter(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+
+	for _, b := range p {
+		if len(f.pending) == 0 {
+			if b == 0x1b {
+				f.pending = append(f.pending, b)
+				continue
+			}
+			out = append(out, b)
+			continue
+		}
+
+		f.pending = append(f.pending, b)
+
+		if len(f.pending) == 1 {
+			continue
+		}
+
+		if len(f.pending) == 2 {
+			if b != '[' {
+				// Not a CSI sequence we recognize; pass it through as-is.
+				out = append(out, f.pending...)
+				f.pending = nil
+			}
+			continue
+		}
+
+		if csiFinalByte(b) {
+			if f.keepANSI && b == 'm' {
+				out = append(out, f.pending...)
+			}
+			f.pending = nil
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import "testing"
+
+func TestAnsiFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keepANSI bool
+		in       string
+		want     string
+	}{
+		{"plain text unchanged", true, "hello world", "hello world"},
+		{"plain text unchanged, stripped", false, "hello world", "hello world"},
+		{"sgr kept on tty", true, "\x1b[31mred\x1b[0m", "\x1b[31mred\x1b[0m"},
+		{"sgr stripped on non-tty", false, "\x1b[31mred\x1b[0m", "red"},
+		{"cursor movement stripped on tty", true, "a\x1b[2Ab", "ab"},
+		{"clear screen stripped on tty", true, "a\x1b[2Jb", "ab"},
+		{"cursor movement stripped on non-tty", false, "a\x1b[2Ab", "ab"},
+		{"lone escape byte passed through", true, "a\x1bb", "a\x1bb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := ansiFilter{keepANSI: tt.keepANSI}
+			if got := string(f.filter([]byte(tt.in))); got != tt.want {
+				t.Errorf("filter(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnsiFilterBuffersAcrossCalls(t *testing.T) {
+	f := ansiFilter{keepANSI: true}
+
+	var out []byte
+	out = append(out, f.filter([]byte("a\x1b["))...)
+	out = append(out, f.filter([]byte("31"))...)
+	out = append(out, f.filter([]byte("mred\x1b[0m"))...)
+
+	want := "a\x1b[31mred\x1b[0m"
+	if string(out) != want {
+		t.Errorf("filter across calls = %q, want %q", string(out), want)
+	}
+}
+
+func TestAnsiFilterBuffersAcrossCallsStripped(t *testing.T) {
+	f := ansiFilter{keepANSI: false}
+
+	var out []byte
+	out = append(out, f.filter([]byte("a\x1b[2"))...)
+	out = append(out, f.filter([]byte("J"))...)
+	out = append(out, f.filter([]byte("b"))...)
+
+	want := "ab"
+	if string(out) != want {
+		t.Errorf("filter across calls = %q, want %q", string(out), want)
+	}
+}
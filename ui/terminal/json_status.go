@@ -0,0 +1,116 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+// jsonStatusEvent is the schema written, one per line, by
+// jsonStatusOutput: a stable machine-readable feed of build progress for
+// IDEs, dashboards, and CI wrappers that would otherwise have to scrape
+// the ANSI status line.
+type jsonStatusEvent struct {
+	Event       string  `json:"event"`
+	Time        float64 `json:"time"`
+	Level       string  `json:"level,omitempty"`
+	Message     string  `json:"message,omitempty"`
+	ActionID    string  `json:"action_id,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Command     string  `json:"command,omitempty"`
+	Started     int     `json:"started,omitempty"`
+	Finished    int     `json:"finished,omitempty"`
+	Running     int     `json:"running,omitempty"`
+	Total       int     `json:"total,omitempty"`
+	ExitCode    *int    `json:"exit_code,omitempty"`
+	Output      string  `json:"output,omitempty"`
+}
+
+type jsonStatusOutput struct {
+	writer io.Writer
+	start  time.Time
+
+	lock sync.Mutex
+}
+
+// NewJSONStatusOutput returns a StatusOutput that writes one JSON object
+// per line to w for every Message, StartAction, FinishAction, and Flush
+// event.
+func NewJSONStatusOutput(w io.Writer) status.StatusOutput {
+	return &jsonStatusOutput{
+		writer: w,
+		start:  time.Now(),
+	}
+}
+
+func (s *jsonStatusOutput) emit(event jsonStatusEvent) {
+	event.Time = time.Since(s.start).Seconds()
+	event.Started = event.Finished + event.Running
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	json.NewEncoder(s.writer).Encode(event)
+}
+
+func (s *jsonStatusOutput) Message(level status.MsgLevel, message string) {
+	s.emit(jsonStatusEvent{
+		Event:   "message",
+		Level:   fmt.Sprintf("%d", int(level)),
+		Message: message,
+	})
+}
+
+func (s *jsonStatusOutput) StartAction(action *status.Action, counts status.Counts) {
+	s.emit(jsonStatusEvent{
+		Event:       "start_action",
+		ActionID:    fmt.Sprintf("%p", action),
+		Description: action.Description,
+		Command:     action.Command,
+		Finished:    counts.FinishedCount,
+		Running:     counts.RunningActions,
+		Total:       counts.TotalActions,
+	})
+}
+
+func (s *jsonStatusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
+	exitCode := result.ExitCode
+	s.emit(jsonStatusEvent{
+		Event:       "finish_action",
+		ActionID:    fmt.Sprintf("%p", result.Action),
+		Description: result.Description,
+		Command:     result.Command,
+		Finished:    counts.FinishedCount,
+		Running:     counts.RunningActions,
+		Total:       counts.TotalActions,
+		ExitCode:    &exitCode,
+		Output:      result.Output,
+	})
+}
+
+func (s *jsonStatusOutput) Flush() {
+	s.emit(jsonStatusEvent{Event: "flush"})
+}
+
+func (s *jsonStatusOutput) Write(p []byte) (int, error) {
+	s.emit(jsonStatusEvent{Event: "output", Output: string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"android/soong/ui/status"
+)
+
+// decodeJSONEvents parses the newline-delimited JSON objects written by a
+// jsonStatusOutput.
+func decodeJSONEvents(t *testing.T, buf *bytes.Buffer) []jsonStatusEvent {
+	t.Helper()
+
+	var events []jsonStatusEvent
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event jsonStatusEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanning output: %v", err)
+	}
+	return events
+}
+
+func TestJSONStatusOutput(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONStatusOutput(&buf)
+
+	action := &status.Action{Description: "compile foo.c", Command: "clang foo.c"}
+	counts := status.Counts{FinishedCount: 3, RunningActions: 2, TotalActions: 10}
+
+	s.Message(status.ErrorLvl, "uh oh")
+	s.StartAction(action, counts)
+	s.FinishAction(status.ActionResult{
+		Action:      action,
+		Description: action.Description,
+		Command:     action.Command,
+		Output:      "foo.c:1: warning",
+		ExitCode:    1,
+	}, counts)
+	s.Write([]byte("raw subprocess output\n"))
+	s.Flush()
+
+	events := decodeJSONEvents(t, &buf)
+	if len(events) != 5 {
+		t.Fatalf("got %d events, want 5: %+v", len(events), events)
+	}
+
+	if events[0].Event != "message" || events[0].Message != "uh oh" {
+		t.Errorf("events[0] = %+v, want message %q", events[0], "uh oh")
+	}
+
+	start := events[1]
+	if start.Event != "start_action" || start.Description != "compile foo.c" ||
+		start.Finished != 3 || start.Running != 2 || start.Total != 10 {
+		t.Errorf("events[1] = %+v, want start_action with counts 3/2/10", start)
+	}
+	if start.Started != start.Finished+start.Running {
+		t.Errorf("events[1].Started = %d, want Finished(%d)+Running(%d)", start.Started, start.Finished, start.Running)
+	}
+	if start.ActionID == "" {
+		t.Errorf("events[1].ActionID is empty")
+	}
+
+	finish := events[2]
+	if finish.Event != "finish_action" || finish.Output != "foo.c:1: warning" {
+		t.Errorf("events[2] = %+v, want finish_action with the warning output", finish)
+	}
+	if finish.ExitCode == nil || *finish.ExitCode != 1 {
+		t.Errorf("events[2].ExitCode = %v, want 1", finish.ExitCode)
+	}
+	if finish.ActionID != start.ActionID {
+		t.Errorf("events[2].ActionID = %q, want match with start action %q", finish.ActionID, start.ActionID)
+	}
+
+	if events[3].Event != "output" || events[3].Output != "raw subprocess output\n" {
+		t.Errorf("events[3] = %+v, want output event", events[3])
+	}
+
+	if events[4].Event != "flush" {
+		t.Errorf("events[4] = %+v, want flush event", events[4])
+	}
+}
+
+// fakeStatusOutput records every call made to it, for verifying that
+// MultiStatusOutput fans out correctly.
+type fakeStatusOutput struct {
+	calls []string
+}
+
+func (f *fakeStatusOutput) Message(level status.MsgLevel, message string) {
+	f.calls = append(f.calls, "message:"+message)
+}
+
+func (f *fakeStatusOutput) StartAction(action *status.Action, counts status.Counts) {
+	f.calls = append(f.calls, "start:"+action.Description)
+}
+
+func (f *fakeStatusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
+	f.calls = append(f.calls, "finish:"+result.Description)
+}
+
+func (f *fakeStatusOutput) Flush() {
+	f.calls = append(f.calls, "flush")
+}
+
+func (f *fakeStatusOutput) Write(p []byte) (int, error) {
+	f.calls = append(f.calls, "write:"+string(p))
+	return len(p), nil
+}
+
+func TestMultiStatusOutput(t *testing.T) {
+	a := &fakeStatusOutput{}
+	b := &fakeStatusOutput{}
+	c := &fakeStatusOutput{}
+
+	m := NewMultiStatusOutput(a, b, c)
+
+	action := &status.Action{Description: "compile foo.c"}
+	m.Message(status.StatusLvl, "hello")
+	m.StartAction(action, status.Counts{})
+	m.FinishAction(status.ActionResult{Description: "compile foo.c"}, status.Counts{})
+	m.Write([]byte("output"))
+	m.Flush()
+
+	want := []string{
+		"message:hello",
+		"start:compile foo.c",
+		"finish:compile foo.c",
+		"write:output",
+		"flush",
+	}
+
+	for _, fake := range []*fakeStatusOutput{a, b, c} {
+		if len(fake.calls) != len(want) {
+			t.Fatalf("got %d calls, want %d: %v", len(fake.calls), len(want), fake.calls)
+		}
+		for i, call := range want {
+			if fake.calls[i] != call {
+				t.Errorf("calls[%d] = %q, want %q", i, fake.calls[i], call)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import "android/soong/ui/status"
+
+// multiStatusOutput fans out every status event to a list of
+// StatusOutputs, for example a smartStatusOutput on the TTY alongside a
+// jsonStatusOutput streaming to a file or a socket for some other tool to
+// consume.
+type multiStatusOutput struct {
+	outputs []status.StatusOutput
+}
+
+// NewMultiStatusOutput returns a StatusOutput that forwards every event to
+// each of outputs, in order.
+func NewMultiStatusOutput(outputs ...status.StatusOutput) status.StatusOutput {
+	return &multiStatusOutput{outputs: outputs}
+}
+
+func (m *multiStatusOutput) Message(level status.MsgLevel, message string) {
+	for _, output := range m.outputs {
+		output.Message(level, message)
+	}
+}
+
+func (m *multiStatusOutput) StartAction(action *status.Action, counts status.Counts) {
+	for _, output := range m.outputs {
+		output.StartAction(action, counts)
+	}
+}
+
+func (m *multiStatusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
+	for _, output := range m.outputs {
+		output.FinishAction(result, counts)
+	}
+}
+
+func (m *multiStatusOutput) Flush() {
+	for _, output := range m.outputs {
+		output.Flush()
+	}
+}
+
+func (m *multiStatusOutput) Write(p []byte) (int, error) {
+	for _, output := range m.outputs {
+		output.Write(p)
+	}
+	return len(p), nil
+}
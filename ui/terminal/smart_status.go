@@ -19,13 +19,23 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"android/soong/ui/status"
 )
 
+// tableHeightEnvVar names the environment variable that reserves the
+// bottom N rows of the terminal for a live table of the longest currently
+// running actions. It's most useful at the tail end of a build, when
+// parallelism drops and it's not obvious what the last few running
+// actions are waiting on.
+const tableHeightEnvVar = "SOONG_UI_TABLE_HEIGHT"
+
 type smartStatusOutput struct {
 	writer    io.Writer
 	formatter formatter
@@ -35,8 +45,22 @@ type smartStatusOutput struct {
 	haveBlankLine bool
 
 	termWidth       int
+	termHeight      int
 	sigwinch        chan os.Signal
 	sigwinchHandled chan bool
+
+	// tableHeight is the number of rows reserved at the bottom of the
+	// terminal for the running-actions table. 0 disables the table.
+	tableHeight int
+	running     map[*status.Action]time.Time
+	tableTicker *time.Ticker
+	tableDone   chan bool
+	tableWG     sync.WaitGroup
+
+	// ansi filters escape sequences out of subprocess output written
+	// through print/Write, keeping color codes only when writer is a
+	// real terminal.
+	ansi ansiFilter
 }
 
 // NewSmartStatusOutput returns a StatusOutput that represents the
@@ -50,12 +74,20 @@ func NewSmartStatusOutput(w io.Writer, formatter formatter) status.StatusOutput
 		haveBlankLine: true,
 
 		sigwinch: make(chan os.Signal),
+
+		running: make(map[*status.Action]time.Time),
+	}
+
+	if n, err := strconv.Atoi(os.Getenv(tableHeightEnvVar)); err == nil && n > 0 {
+		s.tableHeight = n
 	}
 
 	s.updateTermSize()
 
 	s.startSigwinch()
 
+	s.startTable()
+
 	return s
 }
 
@@ -87,6 +119,8 @@ func (s *smartStatusOutput) StartAction(action *status.Action, counts status.Cou
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.running[action] = time.Now()
+
 	s.statusLine(progress + str)
 }
 
@@ -103,6 +137,8 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	delete(s.running, result.Action)
+
 	if output != "" {
 		s.statusLine(progress)
 		s.requestLine()
@@ -114,9 +150,16 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 
 func (s *smartStatusOutput) Flush() {
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
 	s.stopSigwinch()
+	s.lock.Unlock()
+
+	// stopTable must run with s.lock free: the table redraw goroutine may
+	// be blocked trying to acquire it, and stopTable waits for that
+	// goroutine to exit before it's safe to reset the scroll region.
+	s.stopTable()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
 	s.requestLine()
 }
@@ -136,6 +179,8 @@ func (s *smartStatusOutput) requestLine() {
 }
 
 func (s *smartStatusOutput) print(str string) {
+	str = string(s.ansi.filter([]byte(str)))
+
 	if !s.haveBlankLine {
 		fmt.Fprint(s.writer, "\r", "\x1b[K")
 		s.haveBlankLine = true
@@ -166,14 +211,48 @@ func (s *smartStatusOutput) statusLine(str string) {
 	s.haveBlankLine = false
 }
 
+// elide shortens str to fit within s.termWidth visible columns by cutting
+// out its middle and replacing it with "...", the same way Ninja elides
+// long status lines. This keeps the informative leading progress prefix
+// and trailing target name visible instead of just cutting off the tail.
+// Width is measured in runes, skipping over embedded ANSI SGR escape
+// sequences, so neither multi-byte UTF-8 targets nor color codes get
+// sliced apart.
 func (s *smartStatusOutput) elide(str string) string {
-	if len(str) > s.termWidth {
-		// TODO: Just do a max. Ninja elides the middle, but that's
-		// more complicated and these lines aren't that important.
-		str = str[:s.termWidth]
+	runes := []rune(str)
+
+	// visible holds the index in runes of every rune that counts toward
+	// the line's printed width, i.e. everything outside of an SGR escape.
+	visible := make([]int, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\x1b' && i+1 < len(runes) && runes[i+1] == '[' {
+			j := i + 2
+			for j < len(runes) && runes[j] != 'm' {
+				j++
+			}
+			i = j
+			continue
+		}
+		visible = append(visible, i)
 	}
 
-	return str
+	if len(visible) <= s.termWidth {
+		return str
+	}
+
+	if s.termWidth < 5 {
+		// Terminal too narrow to elide sensibly; fall back to a hard cut,
+		// by rune so we don't slice a multi-byte codepoint in half.
+		return string(runes[:s.termWidth])
+	}
+
+	keepLeft := (s.termWidth - 3) / 2
+	keepRight := s.termWidth - 3 - keepLeft
+
+	leftEnd := visible[keepLeft-1]
+	rightStart := visible[len(visible)-keepRight]
+
+	return string(runes[:leftEnd+1]) + "..." + string(runes[rightStart:])
 }
 
 func (s *smartStatusOutput) startSigwinch() {
@@ -198,5 +277,168 @@ func (s *smartStatusOutput) stopSigwinch() {
 func (s *smartStatusOutput) updateTermSize() {
 	if w, ok := termWidth(s.writer); ok {
 		s.termWidth = w
+		s.ansi.keepANSI = true
+	} else {
+		s.ansi.keepANSI = false
+	}
+	if h, ok := termHeight(s.writer); ok {
+		s.termHeight = h
+	}
+	s.updateScrollRegion()
+}
+
+// startTable reserves the bottom tableHeight rows of the terminal for the
+// running-actions table and starts a goroutine that redraws it
+// periodically. It's a no-op if tableHeight is 0.
+func (s *smartStatusOutput) startTable() {
+	if s.tableHeight <= 0 || s.termHeight <= 0 {
+		return
+	}
+
+	// Scroll the normal output up to make room for the table, then pin
+	// the scroll region above it so later output never overwrites it.
+	fmt.Fprintf(s.writer, "\x1b[%dS", s.tableHeight)
+	s.updateScrollRegion()
+
+	// ticker and done are captured by the goroutine below instead of read
+	// off s.tableTicker/s.tableDone on every loop iteration, so that
+	// stopTable can reassign those fields the moment it stops the ticker
+	// without racing the goroutine (and without it ever dereferencing a
+	// ticker that's been nilled out from under it).
+	ticker := time.NewTicker(time.Second)
+	done := make(chan bool)
+	s.tableTicker = ticker
+	s.tableDone = done
+
+	s.tableWG.Add(1)
+	go func() {
+		defer s.tableWG.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.lock.Lock()
+				s.renderTable()
+				s.lock.Unlock()
+			}
+		}
+	}()
+}
+
+// stopTable stops the periodic redraw, restores the scroll region to the
+// full screen, and clears the table pane. It must be called with s.lock
+// not held: the redraw goroutine may be blocked waiting to acquire it, and
+// stopTable blocks until that goroutine has fully exited before touching
+// the scroll region, so that a stale redraw can't land after the pane is
+// cleared.
+func (s *smartStatusOutput) stopTable() {
+	if s.tableHeight <= 0 {
+		return
+	}
+
+	if s.tableTicker != nil {
+		s.tableTicker.Stop()
+		close(s.tableDone)
+	}
+	s.tableWG.Wait()
+
+	// Safe to clear now: the goroutine that reads these has exited.
+	s.tableTicker = nil
+	s.tableDone = nil
+
+	if s.termHeight <= 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	fmt.Fprint(s.writer, "\x1b[r")
+	s.clearTable()
+}
+
+// updateScrollRegion sets the scroll region to exclude the bottom
+// tableHeight rows reserved for the table, so that normal scrolling
+// output never overwrites it. Called on startup and on SIGWINCH.
+func (s *smartStatusOutput) updateScrollRegion() {
+	if s.tableHeight <= 0 || s.termHeight <= 0 {
+		return
+	}
+
+	bottom := s.termHeight - s.tableHeight
+	if bottom < 1 {
+		bottom = 1
+	}
+	fmt.Fprintf(s.writer, "\x1b[1;%dr", bottom)
+}
+
+// clearTable blanks out the reserved table rows without disturbing the
+// cursor's position in the normal scrolling region.
+func (s *smartStatusOutput) clearTable() {
+	if s.termHeight <= 0 {
+		return
+	}
+
+	top := s.termHeight - s.tableHeight + 1
+	fmt.Fprint(s.writer, "\x1b7")
+	for i := 0; i < s.tableHeight; i++ {
+		fmt.Fprintf(s.writer, "\x1b[%d;1H\x1b[K", top+i)
+	}
+	fmt.Fprint(s.writer, "\x1b8")
+}
+
+// runningAction is one row of the running-actions table: an action along
+// with how long it's been running.
+type runningAction struct {
+	action  *status.Action
+	elapsed time.Duration
+}
+
+// longestRunning returns the top n entries of running sorted by descending
+// elapsed time, for display in the running-actions table. It's a pure
+// function of its inputs so the sort/truncate logic can be unit tested
+// without a writer or a real terminal.
+func longestRunning(running map[*status.Action]time.Time, now time.Time, n int) []runningAction {
+	sorted := make([]runningAction, 0, len(running))
+	for action, start := range running {
+		sorted = append(sorted, runningAction{action, now.Sub(start)})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].elapsed > sorted[j].elapsed
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// renderTable redraws the reserved pane with the tableHeight longest
+// currently running actions, sorted by elapsed time, without disturbing
+// the cursor's position in the normal scrolling region.
+func (s *smartStatusOutput) renderTable() {
+	if s.tableHeight <= 0 || s.termHeight <= 0 {
+		return
+	}
+
+	running := longestRunning(s.running, time.Now(), s.tableHeight)
+
+	top := s.termHeight - s.tableHeight + 1
+
+	fmt.Fprint(s.writer, "\x1b7")
+	for i := 0; i < s.tableHeight; i++ {
+		fmt.Fprintf(s.writer, "\x1b[%d;1H\x1b[K", top+i)
+		if i < len(running) {
+			str := running[i].action.Description
+			if str == "" {
+				str = running[i].action.Command
+			}
+			line := fmt.Sprintf(" %6.1fs  %s", running[i].elapsed.Seconds(), str)
+			if s.termWidth > 0 {
+				line = s.elide(line)
+			}
+			fmt.Fprint(s.writer, line)
+		}
 	}
+	fmt.Fprint(s.writer, "\x1b8")
 }
@@ -0,0 +1,135 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+func TestSmartStatusOutputElide(t *testing.T) {
+	tests := []struct {
+		name      string
+		termWidth int
+		str       string
+		want      string
+	}{
+		{"fits", 20, "short line", "short line"},
+		{"exact width", 10, "0123456789", "0123456789"},
+		{"elides middle", 10, "0123456789abcdefghij", "012...ghij"},
+		{"odd width elides middle", 11, "0123456789abcdefghij", "0123...ghij"},
+		{"unicode not split", 10, "café0123456789beignet", "caf...gnet"},
+		{"ansi ignored when measuring", 10, "\x1b[1m0123456789abcdefghij\x1b[0m", "\x1b[1m012...ghij\x1b[0m"},
+		{"narrow fallback hard cut", 4, "0123456789", "0123"},
+		{"narrow fallback keeps codepoints whole", 4, "café0123456789", "café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &smartStatusOutput{termWidth: tt.termWidth}
+			if got := s.elide(tt.str); got != tt.want {
+				t.Errorf("elide(%q) with termWidth=%d = %q, want %q", tt.str, tt.termWidth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongestRunning(t *testing.T) {
+	now := time.Now()
+
+	fast := &status.Action{Description: "fast"}
+	medium := &status.Action{Description: "medium"}
+	slow := &status.Action{Description: "slow"}
+
+	running := map[*status.Action]time.Time{
+		fast:   now.Add(-1 * time.Second),
+		medium: now.Add(-5 * time.Second),
+		slow:   now.Add(-10 * time.Second),
+	}
+
+	t.Run("sorts descending by elapsed", func(t *testing.T) {
+		got := longestRunning(running, now, 3)
+		want := []*status.Action{slow, medium, fast}
+		if len(got) != len(want) {
+			t.Fatalf("longestRunning() returned %d entries, want %d", len(got), len(want))
+		}
+		for i, action := range want {
+			if got[i].action != action {
+				t.Errorf("longestRunning()[%d].action = %q, want %q", i, got[i].action.Description, action.Description)
+			}
+		}
+	})
+
+	t.Run("truncates to n", func(t *testing.T) {
+		got := longestRunning(running, now, 2)
+		if len(got) != 2 {
+			t.Fatalf("longestRunning() with n=2 returned %d entries, want 2", len(got))
+		}
+		if got[0].action != slow || got[1].action != medium {
+			t.Errorf("longestRunning() with n=2 = %q, %q, want slow, medium", got[0].action.Description, got[1].action.Description)
+		}
+	})
+
+	t.Run("n larger than running", func(t *testing.T) {
+		got := longestRunning(running, now, 10)
+		if len(got) != 3 {
+			t.Errorf("longestRunning() with n=10 returned %d entries, want 3", len(got))
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		got := longestRunning(map[*status.Action]time.Time{}, now, 3)
+		if len(got) != 0 {
+			t.Errorf("longestRunning() on empty map returned %d entries, want 0", len(got))
+		}
+	})
+
+	t.Run("elapsed is measured from now", func(t *testing.T) {
+		got := longestRunning(map[*status.Action]time.Time{fast: now.Add(-2 * time.Second)}, now, 1)
+		if got[0].elapsed != 2*time.Second {
+			t.Errorf("elapsed = %v, want 2s", got[0].elapsed)
+		}
+	})
+}
+
+// TestStartStopTable is a regression test for the redraw goroutine's
+// lifecycle: starting and immediately stopping the table must not panic or
+// deadlock, and stopTable must leave tableTicker/tableDone cleared only
+// once the goroutine has actually exited.
+func TestStartStopTable(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		var buf bytes.Buffer
+		s := &smartStatusOutput{
+			writer:      &buf,
+			termHeight:  24,
+			termWidth:   80,
+			tableHeight: 3,
+			running:     make(map[*status.Action]time.Time),
+		}
+
+		s.startTable()
+		s.stopTable()
+
+		if s.tableTicker != nil {
+			t.Fatalf("iteration %d: tableTicker not cleared after stopTable", i)
+		}
+		if s.tableDone != nil {
+			t.Fatalf("iteration %d: tableDone not cleared after stopTable", i)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+// statusFormatEnvVar names the environment variable that, if set,
+// overrides the default "[NNN% f/t]" progress prefix with a
+// NINJA_STATUS-style format string.
+const statusFormatEnvVar = "NINJA_STATUS"
+
+// formatter converts status events into the strings that get printed by a
+// StatusOutput.
+type formatter struct {
+	statusFormat string
+	start        time.Time
+}
+
+// newFormatter returns a formatter that reads its progress format from the
+// NINJA_STATUS environment variable, falling back to the default
+// "[NNN% f/t]" format when it's unset.
+func newFormatter() formatter {
+	return formatter{
+		statusFormat: os.Getenv(statusFormatEnvVar),
+		start:        time.Now(),
+	}
+}
+
+func (f formatter) message(level status.MsgLevel, message string) string {
+	if level >= status.ErrorLvl {
+		return "FAILED: " + message
+	}
+	return message
+}
+
+// progress returns the prefix printed before every status line, following
+// statusFormat if one was provided, or the default "[NNN% f/t]" format
+// otherwise.
+func (f formatter) progress(counts status.Counts) string {
+	if f.statusFormat == "" {
+		total := counts.TotalActions
+		if total <= 0 {
+			total = 1
+		}
+		percentage := 100 * counts.FinishedCount / total
+		return fmt.Sprintf("[%3d%% %d/%d] ", percentage, counts.FinishedCount, counts.TotalActions)
+	}
+
+	return f.formatStatus(counts)
+}
+
+// formatStatus implements a small subset of ninja's NINJA_STATUS
+// directives: %s (started), %t (total), %r (running), %u (unstarted),
+// %f (finished), %o (finished/elapsed rate), %p (percent), %e (elapsed
+// seconds), and %%. An unknown directive %X is rendered as
+// "unknown placeholder 'X'" so mistakes in the format string are obvious
+// instead of silently dropped.
+func (f formatter) formatStatus(counts status.Counts) string {
+	started := counts.FinishedCount + counts.RunningActions
+	unstarted := counts.TotalActions - started
+	elapsed := time.Since(f.start).Seconds()
+
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(counts.FinishedCount) / elapsed
+	}
+
+	var percent int
+	if counts.TotalActions > 0 {
+		percent = 100 * counts.FinishedCount / counts.TotalActions
+	}
+
+	runes := []rune(f.statusFormat)
+	var buf strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' || i+1 >= len(runes) {
+			buf.WriteRune(runes[i])
+			continue
+		}
+
+		i++
+		switch runes[i] {
+		case 's':
+			fmt.Fprintf(&buf, "%d", started)
+		case 't':
+			fmt.Fprintf(&buf, "%d", counts.TotalActions)
+		case 'r':
+			fmt.Fprintf(&buf, "%d", counts.RunningActions)
+		case 'u':
+			fmt.Fprintf(&buf, "%d", unstarted)
+		case 'f':
+			fmt.Fprintf(&buf, "%d", counts.FinishedCount)
+		case 'o':
+			fmt.Fprintf(&buf, "%.1f", rate)
+		case 'p':
+			fmt.Fprintf(&buf, "%3d%%", percent)
+		case 'e':
+			fmt.Fprintf(&buf, "%.3f", elapsed)
+		case '%':
+			buf.WriteRune('%')
+		default:
+			fmt.Fprintf(&buf, "unknown placeholder '%c'", runes[i])
+		}
+	}
+
+	return buf.String()
+}
+
+func (f formatter) result(result status.ActionResult) string {
+	return result.Output
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+func TestFormatterFormatStatus(t *testing.T) {
+	counts := status.Counts{
+		FinishedCount:  40,
+		RunningActions: 10,
+		TotalActions:   100,
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"started", "%s", "50"},
+		{"total", "%t", "100"},
+		{"running", "%r", "10"},
+		{"unstarted", "%u", "50"},
+		{"finished", "%f", "40"},
+		{"percent", "%p", " 40%"},
+		{"literal percent", "100%%", "100%"},
+		{"mixed", "[%p] %f/%t", "[ 40%] 40/100"},
+		{"unknown directive", "%z", "unknown placeholder 'z'"},
+		{"trailing percent", "abc%", "abc%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := formatter{statusFormat: tt.format, start: time.Now()}
+			if got := f.formatStatus(counts); got != tt.want {
+				t.Errorf("formatStatus(%q) = %q, want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatterFormatStatusElapsedAndRate(t *testing.T) {
+	counts := status.Counts{FinishedCount: 10, TotalActions: 100}
+	f := formatter{statusFormat: "%e %o", start: time.Now().Add(-2 * time.Second)}
+
+	got := f.formatStatus(counts)
+	if !regexp.MustCompile(`^\d+\.\d{3} \d+\.\d$`).MatchString(got) {
+		t.Errorf("formatStatus(%q) = %q, want elapsed seconds (3 decimals) and rate (1 decimal)", f.statusFormat, got)
+	}
+}
+
+func TestFormatterProgressDefaultFormat(t *testing.T) {
+	f := formatter{start: time.Now()}
+	counts := status.Counts{FinishedCount: 25, TotalActions: 100}
+
+	want := "[ 25% 25/100] "
+	if got := f.progress(counts); got != want {
+		t.Errorf("progress() = %q, want %q", got, want)
+	}
+}